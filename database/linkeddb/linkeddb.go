@@ -3,20 +3,29 @@
 
 package linkeddb
 
-// LinkedDB is deprecated, with the implementation here violating the original
-// design considerations for a significant performance boost, while still
-// maintaining reverse compatibility to how it was actually used in production.
+// LinkedDB's default implementation trades the original design's
+// doubly-linked-list invariants for a significant performance boost, while
+// still maintaining reverse compatibility to how it was actually used in
+// production. Callers that need deterministic, FIFO iteration order (e.g.
+// mempool-like replay) should use NewOrdered instead, which restores the
+// original semantics at the cost of maintaining the link pointers on every
+// Put and Delete.
 
 import (
+	"bytes"
+
 	"github.com/ava-labs/avalanchego/database"
 )
 
 var (
-	headKey       = []byte{0x01} // deprecated
+	headKey       = []byte{0x01}
+	tailKey       = []byte{0x02}
 	nodeKeyPrefix = byte(0x00)
 
 	_ LinkedDB          = (*linkedDB)(nil)
+	_ LinkedDB          = (*orderedLinkedDB)(nil)
 	_ database.Iterator = (*iterator)(nil)
+	_ database.Iterator = (*orderedIterator)(nil)
 )
 
 // LinkedDB provides a key value interface while allowing iteration.
@@ -29,6 +38,12 @@ type LinkedDB interface {
 
 	NewIterator() database.Iterator
 	NewIteratorWithStart(start []byte) database.Iterator
+	NewIteratorWithPrefix(prefix []byte) database.Iterator
+	NewIteratorWithStartAndPrefix(start, prefix []byte) database.Iterator
+
+	// NewReverseIterator returns an iterator that walks from the tail of
+	// the list to the head.
+	NewReverseIterator() database.Iterator
 }
 
 type linkedDB struct {
@@ -108,6 +123,49 @@ func NewDefault(db database.Database) LinkedDB {
 	return New(db)
 }
 
+// NewOrdered returns a LinkedDB that maintains true insertion-order
+// iteration: Put appends to the tail, Delete splices the removed node out
+// of its neighbors, and iteration walks the Next pointers starting from
+// the head persisted under [headKey]. This costs an extra read/write per
+// mutation relative to New, so it should only be used by callers that
+// actually rely on FIFO ordering.
+//
+// Put guards against the single stray key left over from a store that was
+// partially written via New before switching to NewOrdered, but a store
+// with more than a handful of such pre-existing raw entries should still
+// go through Upgrade; see Put and Upgrade for the details of the hazard
+// this guards against.
+func NewOrdered(db database.Database) LinkedDB {
+	return &orderedLinkedDB{
+		db: db,
+	}
+}
+
+// Upgrade migrates an existing LinkedDB created with New into one that can
+// be safely used with NewOrdered, by replaying its raw key-order contents
+// through appendNode so that the doubly-linked-list pointers are populated.
+// This deliberately bypasses Put's existing-key check: every entry being
+// migrated already has a raw, unlinked node on disk (written by the fast
+// path's Put), and although Put's isUnlinked guard would eventually link
+// each one in on its next write, Upgrade links the whole store in one pass
+// up front instead of leaving entries invisible to iteration until they
+// happen to be re-Put. The caller must ensure there are no concurrent
+// writers to [db] during the migration, and that [db] has not already been
+// upgraded.
+func Upgrade(db database.Database) error {
+	unordered := New(db)
+	ordered := &orderedLinkedDB{db: db}
+
+	it := unordered.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if err := ordered.appendNode(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
 func (ldb *linkedDB) Has(key []byte) (bool, error) {
 	return ldb.db.Has(nodeKey(key))
 }
@@ -163,3 +221,431 @@ func (ldb *linkedDB) NewIterator() database.Iterator {
 func (ldb *linkedDB) NewIteratorWithStart(start []byte) database.Iterator {
 	return &iterator{ldb.db.NewIteratorWithStart(start)}
 }
+
+// NewIteratorWithPrefix returns an iterator that only returns keys with
+// [prefix].
+func (ldb *linkedDB) NewIteratorWithPrefix(prefix []byte) database.Iterator {
+	return &iterator{ldb.db.NewIteratorWithPrefix(nodeKey(prefix))}
+}
+
+// NewIteratorWithStartAndPrefix returns an iterator that starts at [start]
+// and only returns keys with [prefix].
+func (ldb *linkedDB) NewIteratorWithStartAndPrefix(start, prefix []byte) database.Iterator {
+	return &iterator{ldb.db.NewIteratorWithStartAndPrefix(nodeKey(start), nodeKey(prefix))}
+}
+
+// NewReverseIterator returns an iterator that walks key order in reverse.
+// The fast path has no linkage to walk, so this buffers the forward
+// iteration and replays it tail to head.
+func (ldb *linkedDB) NewReverseIterator() database.Iterator {
+	return newBufferedReverseIterator(ldb.NewIterator())
+}
+
+// orderedLinkedDB is a LinkedDB that maintains the Next/Previous pointers
+// on [node] so that iteration follows true insertion order rather than the
+// underlying database's key order.
+type orderedLinkedDB struct {
+	// db is the underlying database that this list is stored in.
+	db database.Database
+}
+
+func (ldb *orderedLinkedDB) getNode(key []byte) (node, error) {
+	nodeBytes, err := ldb.db.Get(nodeKey(key))
+	if err != nil {
+		return node{}, err
+	}
+	var n node
+	_, err = Codec.Unmarshal(nodeBytes, &n)
+	return n, err
+}
+
+func (ldb *orderedLinkedDB) putNode(key []byte, n node) error {
+	nodeBytes, err := Codec.Marshal(CodecVersion, n)
+	if err != nil {
+		return err
+	}
+	return ldb.db.Put(nodeKey(key), nodeBytes)
+}
+
+func (ldb *orderedLinkedDB) Has(key []byte) (bool, error) {
+	return ldb.db.Has(nodeKey(key))
+}
+
+func (ldb *orderedLinkedDB) Get(key []byte) ([]byte, error) {
+	n, err := ldb.getNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return n.Value, nil
+}
+
+// Put appends [key]/[value] to the tail of the list. If [key] already has a
+// linked node, its value is updated in place and its position in the list
+// is left unchanged. If [key] instead has a raw, unlinked node left over
+// from the fast path (New) that was never migrated with Upgrade, isUnlinked
+// catches it so it gets linked in at the tail here rather than silently
+// becoming Get/Has-able but permanently invisible to iteration.
+func (ldb *orderedLinkedDB) Put(key, value []byte) error {
+	existing, err := ldb.getNode(key)
+	switch {
+	case err == nil && !ldb.isUnlinked(key, existing):
+		existing.Value = value
+		return ldb.putNode(key, existing)
+	case err != nil && err != database.ErrNotFound:
+		return err
+	}
+	return ldb.appendNode(key, value)
+}
+
+// isUnlinked reports whether [n], the node currently stored at [key],
+// looks like it was written by the fast path (New) rather than linked in
+// by NewOrdered: it has neither a Next nor a Previous, and it isn't
+// recorded as the ordered list's sole head-and-tail entry either. This is
+// a best-effort guard for a store that has picked up a handful of
+// fast-path writes since switching to NewOrdered; a store with many such
+// entries should be migrated in bulk with Upgrade instead.
+func (ldb *orderedLinkedDB) isUnlinked(key []byte, n node) bool {
+	if n.HasNext || n.HasPrevious {
+		return false
+	}
+	head, err := ldb.db.Get(headKey)
+	if err != nil || !bytes.Equal(head, key) {
+		return true
+	}
+	tail, err := ldb.db.Get(tailKey)
+	return err != nil || !bytes.Equal(tail, key)
+}
+
+// appendNode unconditionally adds [key]/[value] as a new node at the tail
+// of the list, without checking whether [key] already has a node. This is
+// only safe to call when [key] is known not to already be linked into the
+// list, which Put ensures for its callers and Upgrade ensures by virtue of
+// migrating a store that has no links yet.
+func (ldb *orderedLinkedDB) appendNode(key, value []byte) error {
+	newNode := node{Value: value}
+
+	tail, err := ldb.db.Get(tailKey)
+	switch err {
+	case database.ErrNotFound:
+		// The list is empty, so [key] becomes both the head and the tail.
+		if err := ldb.db.Put(headKey, key); err != nil {
+			return err
+		}
+	case nil:
+		tailNode, err := ldb.getNode(tail)
+		if err != nil {
+			return err
+		}
+		tailNode.HasNext = true
+		tailNode.Next = key
+		if err := ldb.putNode(tail, tailNode); err != nil {
+			return err
+		}
+
+		newNode.HasPrevious = true
+		newNode.Previous = tail
+	default:
+		return err
+	}
+
+	if err := ldb.putNode(key, newNode); err != nil {
+		return err
+	}
+	return ldb.db.Put(tailKey, key)
+}
+
+// Delete splices the node at [key] out of the list, reconnecting its
+// neighbors, and updating [headKey]/[tailKey] if [key] was the head or
+// tail.
+func (ldb *orderedLinkedDB) Delete(key []byte) error {
+	n, err := ldb.getNode(key)
+	if err == database.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ldb.isUnlinked(key, n) {
+		// A raw fast-path node is not part of the list, so there are no
+		// neighbors or head/tail entries to update.
+		return ldb.db.Delete(nodeKey(key))
+	}
+
+	if n.HasPrevious {
+		prev, err := ldb.getNode(n.Previous)
+		if err != nil {
+			return err
+		}
+		prev.HasNext = n.HasNext
+		prev.Next = n.Next
+		if err := ldb.putNode(n.Previous, prev); err != nil {
+			return err
+		}
+	} else if err := ldb.updateHeadOnDelete(n); err != nil {
+		return err
+	}
+
+	if n.HasNext {
+		next, err := ldb.getNode(n.Next)
+		if err != nil {
+			return err
+		}
+		next.HasPrevious = n.HasPrevious
+		next.Previous = n.Previous
+		if err := ldb.putNode(n.Next, next); err != nil {
+			return err
+		}
+	} else if err := ldb.updateTailOnDelete(n); err != nil {
+		return err
+	}
+
+	return ldb.db.Delete(nodeKey(key))
+}
+
+func (ldb *orderedLinkedDB) updateHeadOnDelete(deleted node) error {
+	if deleted.HasNext {
+		return ldb.db.Put(headKey, deleted.Next)
+	}
+	return ldb.db.Delete(headKey)
+}
+
+func (ldb *orderedLinkedDB) updateTailOnDelete(deleted node) error {
+	if deleted.HasPrevious {
+		return ldb.db.Put(tailKey, deleted.Previous)
+	}
+	return ldb.db.Delete(tailKey)
+}
+
+func (ldb *orderedLinkedDB) IsEmpty() (bool, error) {
+	_, err := ldb.HeadKey()
+	if err == database.ErrNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+func (ldb *orderedLinkedDB) HeadKey() ([]byte, error) {
+	return ldb.db.Get(headKey)
+}
+
+func (ldb *orderedLinkedDB) Head() ([]byte, []byte, error) {
+	key, err := ldb.HeadKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := ldb.getNode(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, n.Value, nil
+}
+
+func (ldb *orderedLinkedDB) NewIterator() database.Iterator {
+	head, err := ldb.db.Get(headKey)
+	switch err {
+	case database.ErrNotFound:
+		return &orderedIterator{}
+	case nil:
+		return &orderedIterator{ldb: ldb, hasNext: true, next: head}
+	default:
+		return &orderedIterator{err: err}
+	}
+}
+
+// NewIteratorWithStart returns an iterator that walks the Next pointers
+// starting from the first node whose key is >= [start] in list order, or
+// from the head of the list if [start] is empty. [start] need not be an
+// existing node's key. Nodes are compared with [start] by key bytes, not
+// by list position, and once the first such node is found every later
+// node in the list is returned whatever its key. This matches the
+// database.Database seek only when list order follows key order; nodes
+// with keys below [start] that precede the first match are skipped.
+func (ldb *orderedLinkedDB) NewIteratorWithStart(start []byte) database.Iterator {
+	if len(start) == 0 {
+		return ldb.NewIterator()
+	}
+
+	key, err := ldb.db.Get(headKey)
+	switch err {
+	case database.ErrNotFound:
+		return &orderedIterator{}
+	case nil:
+	default:
+		return &orderedIterator{err: err}
+	}
+
+	for bytes.Compare(key, start) < 0 {
+		n, err := ldb.getNode(key)
+		if err != nil {
+			return &orderedIterator{err: err}
+		}
+		if !n.HasNext {
+			return &orderedIterator{}
+		}
+		key = n.Next
+	}
+
+	return &orderedIterator{
+		ldb:     ldb,
+		hasNext: true,
+		next:    key,
+	}
+}
+
+// orderedIterator walks an orderedLinkedDB via its Next pointers (or its
+// Previous pointers when [reverse] is set) rather than the underlying
+// database's key order.
+type orderedIterator struct {
+	ldb     *orderedLinkedDB
+	key     []byte
+	value   []byte
+	next    []byte
+	hasNext bool
+	reverse bool
+	err     error
+}
+
+func (i *orderedIterator) Next() bool {
+	if i.err != nil || !i.hasNext {
+		i.key = nil
+		i.value = nil
+		return false
+	}
+
+	n, err := i.ldb.getNode(i.next)
+	if err != nil {
+		i.err = err
+		i.key = nil
+		i.value = nil
+		return false
+	}
+
+	i.key = i.next
+	i.value = n.Value
+	if i.reverse {
+		i.hasNext = n.HasPrevious
+		i.next = n.Previous
+	} else {
+		i.hasNext = n.HasNext
+		i.next = n.Next
+	}
+	return true
+}
+
+func (i *orderedIterator) Error() error {
+	return i.err
+}
+
+func (i *orderedIterator) Key() []byte {
+	return i.key
+}
+
+func (i *orderedIterator) Value() []byte {
+	return i.value
+}
+
+func (*orderedIterator) Release() {}
+
+// NewIteratorWithPrefix returns an iterator that only returns keys with
+// [prefix]. Since insertion order is not sorted by key, this walks the
+// full list rather than seeking directly to the prefix.
+func (ldb *orderedLinkedDB) NewIteratorWithPrefix(prefix []byte) database.Iterator {
+	return &prefixFilterIterator{
+		Iterator: ldb.NewIterator(),
+		prefix:   prefix,
+	}
+}
+
+// NewIteratorWithStartAndPrefix returns an iterator that starts at [start]
+// and only returns keys with [prefix].
+func (ldb *orderedLinkedDB) NewIteratorWithStartAndPrefix(start, prefix []byte) database.Iterator {
+	return &prefixFilterIterator{
+		Iterator: ldb.NewIteratorWithStart(start),
+		prefix:   prefix,
+	}
+}
+
+// NewReverseIterator returns an iterator that walks the Previous pointers
+// from the tail of the list to the head.
+func (ldb *orderedLinkedDB) NewReverseIterator() database.Iterator {
+	tail, err := ldb.db.Get(tailKey)
+	switch err {
+	case database.ErrNotFound:
+		return &orderedIterator{}
+	case nil:
+		return &orderedIterator{
+			ldb:     ldb,
+			hasNext: true,
+			next:    tail,
+			reverse: true,
+		}
+	default:
+		return &orderedIterator{err: err}
+	}
+}
+
+// prefixFilterIterator wraps another iterator, skipping entries whose key
+// does not have [prefix]. Unlike a key-ordered NewIteratorWithPrefix, this
+// cannot stop early once the prefix stops matching, since the wrapped
+// iteration order is not sorted by key.
+type prefixFilterIterator struct {
+	database.Iterator
+	prefix []byte
+}
+
+func (i *prefixFilterIterator) Next() bool {
+	for i.Iterator.Next() {
+		if bytes.HasPrefix(i.Iterator.Key(), i.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedReverseIterator buffers all entries from a forward iterator in
+// memory and replays them in reverse. It is used as the fallback
+// reverse-iteration strategy where there is no linkage to walk directly.
+type bufferedReverseIterator struct {
+	keys   [][]byte
+	values [][]byte
+	idx    int
+	err    error
+}
+
+func newBufferedReverseIterator(it database.Iterator) *bufferedReverseIterator {
+	defer it.Release()
+
+	r := &bufferedReverseIterator{}
+	for it.Next() {
+		r.keys = append(r.keys, it.Key())
+		r.values = append(r.values, it.Value())
+	}
+	r.idx = len(r.keys)
+	r.err = it.Error()
+	return r
+}
+
+func (r *bufferedReverseIterator) Next() bool {
+	r.idx--
+	return r.idx >= 0
+}
+
+func (r *bufferedReverseIterator) Error() error {
+	return r.err
+}
+
+func (r *bufferedReverseIterator) Key() []byte {
+	if r.idx < 0 || r.idx >= len(r.keys) {
+		return nil
+	}
+	return r.keys[r.idx]
+}
+
+func (r *bufferedReverseIterator) Value() []byte {
+	if r.idx < 0 || r.idx >= len(r.values) {
+		return nil
+	}
+	return r.values[r.idx]
+}
+
+func (*bufferedReverseIterator) Release() {}
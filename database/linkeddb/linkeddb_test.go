@@ -0,0 +1,264 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package linkeddb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+)
+
+func TestOrderedPutIteratesInInsertionOrder(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	keys := [][]byte{[]byte("c"), []byte("a"), []byte("b")}
+	for _, key := range keys {
+		require.NoError(ldb.Put(key, key))
+	}
+
+	headKey, err := ldb.HeadKey()
+	require.NoError(err)
+	require.Equal(keys[0], headKey)
+
+	it := ldb.NewIterator()
+	defer it.Release()
+	for _, key := range keys {
+		require.True(it.Next())
+		require.Equal(key, it.Key())
+		require.Equal(key, it.Value())
+	}
+	require.False(it.Next())
+	require.NoError(it.Error())
+}
+
+func TestOrderedPutExistingKeyPreservesPosition(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	require.NoError(ldb.Put([]byte("a"), []byte("1")))
+	require.NoError(ldb.Put([]byte("b"), []byte("2")))
+	require.NoError(ldb.Put([]byte("a"), []byte("updated")))
+
+	it := ldb.NewIterator()
+	defer it.Release()
+
+	require.True(it.Next())
+	require.Equal([]byte("a"), it.Key())
+	require.Equal([]byte("updated"), it.Value())
+
+	require.True(it.Next())
+	require.Equal([]byte("b"), it.Key())
+
+	require.False(it.Next())
+}
+
+func TestOrderedDeleteSplicesNode(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	require.NoError(ldb.Put([]byte("a"), []byte("1")))
+	require.NoError(ldb.Put([]byte("b"), []byte("2")))
+	require.NoError(ldb.Put([]byte("c"), []byte("3")))
+
+	require.NoError(ldb.Delete([]byte("b")))
+
+	it := ldb.NewIterator()
+	defer it.Release()
+
+	require.True(it.Next())
+	require.Equal([]byte("a"), it.Key())
+	require.True(it.Next())
+	require.Equal([]byte("c"), it.Key())
+	require.False(it.Next())
+
+	require.NoError(ldb.Delete([]byte("a")))
+	require.NoError(ldb.Delete([]byte("c")))
+
+	isEmpty, err := ldb.IsEmpty()
+	require.NoError(err)
+	require.True(isEmpty)
+}
+
+func TestUpgradeLinksExistingEntries(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	unordered := New(db)
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, key := range keys {
+		require.NoError(unordered.Put(key, key))
+	}
+
+	require.NoError(Upgrade(db))
+
+	ordered := NewOrdered(db)
+	isEmpty, err := ordered.IsEmpty()
+	require.NoError(err)
+	require.False(isEmpty)
+
+	it := ordered.NewIterator()
+	defer it.Release()
+
+	var got [][]byte
+	for it.Next() {
+		got = append(got, append([]byte(nil), it.Key()...))
+	}
+	require.NoError(it.Error())
+	require.Equal(keys, got)
+}
+
+func TestOrderedReverseIterator(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, key := range keys {
+		require.NoError(ldb.Put(key, key))
+	}
+
+	it := ldb.NewReverseIterator()
+	defer it.Release()
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		require.True(it.Next())
+		require.Equal(keys[i], it.Key())
+	}
+	require.False(it.Next())
+	require.NoError(it.Error())
+}
+
+func TestReverseIteratorEmpty(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+
+	it := New(db).NewReverseIterator()
+	defer it.Release()
+	require.False(it.Next())
+	require.NoError(it.Error())
+
+	it = NewOrdered(db).NewReverseIterator()
+	defer it.Release()
+	require.False(it.Next())
+	require.NoError(it.Error())
+}
+
+func TestOrderedNewIteratorWithStartSeeksPastDeletedKey(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	require.NoError(ldb.Put([]byte("a"), []byte("1")))
+	require.NoError(ldb.Put([]byte("b"), []byte("2")))
+	require.NoError(ldb.Put([]byte("c"), []byte("3")))
+	require.NoError(ldb.Delete([]byte("b")))
+
+	it := ldb.NewIteratorWithStart([]byte("b"))
+	defer it.Release()
+
+	require.True(it.Next())
+	require.Equal([]byte("c"), it.Key())
+	require.False(it.Next())
+	require.NoError(it.Error())
+}
+
+func TestOrderedNewIteratorWithStartExactMatch(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	require.NoError(ldb.Put([]byte("a"), []byte("1")))
+	require.NoError(ldb.Put([]byte("b"), []byte("2")))
+	require.NoError(ldb.Put([]byte("c"), []byte("3")))
+
+	it := ldb.NewIteratorWithStart([]byte("b"))
+	defer it.Release()
+
+	require.True(it.Next())
+	require.Equal([]byte("b"), it.Key())
+	require.True(it.Next())
+	require.Equal([]byte("c"), it.Key())
+	require.False(it.Next())
+}
+
+func TestOrderedNewIteratorWithStartPastTailIsEmpty(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	ldb := NewOrdered(db)
+
+	require.NoError(ldb.Put([]byte("a"), []byte("1")))
+
+	it := ldb.NewIteratorWithStart([]byte("z"))
+	defer it.Release()
+
+	require.False(it.Next())
+	require.NoError(it.Error())
+}
+
+func TestOrderedPutLinksStrayFastPathEntry(t *testing.T) {
+	require := require.New(t)
+
+	db := memdb.New()
+	// Write "a" via the fast path, simulating a store that picked up a
+	// write before switching to NewOrdered without going through Upgrade.
+	require.NoError(New(db).Put([]byte("a"), []byte("1")))
+
+	ldb := NewOrdered(db)
+	require.NoError(ldb.Put([]byte("b"), []byte("2")))
+
+	// "a" was never linked in, so iteration should only see "b"...
+	it := ldb.NewIterator()
+	require.True(it.Next())
+	require.Equal([]byte("b"), it.Key())
+	require.False(it.Next())
+	it.Release()
+
+	// ...until it's Put again through the ordered store, which should link
+	// it in at the tail rather than silently updating it in place.
+	require.NoError(ldb.Put([]byte("a"), []byte("updated")))
+
+	it = ldb.NewIterator()
+	defer it.Release()
+	require.True(it.Next())
+	require.Equal([]byte("b"), it.Key())
+	require.True(it.Next())
+	require.Equal([]byte("a"), it.Key())
+	require.Equal([]byte("updated"), it.Value())
+	require.False(it.Next())
+}
+
+func TestNewIteratorWithPrefix(t *testing.T) {
+	require := require.New(t)
+
+	for _, ldb := range []LinkedDB{New(memdb.New()), NewOrdered(memdb.New())} {
+		require.NoError(ldb.Put([]byte("aa"), []byte("1")))
+		require.NoError(ldb.Put([]byte("ab"), []byte("2")))
+		require.NoError(ldb.Put([]byte("ba"), []byte("3")))
+
+		it := ldb.NewIteratorWithPrefix([]byte("a"))
+		defer it.Release()
+
+		var got [][]byte
+		for it.Next() {
+			got = append(got, append([]byte(nil), it.Key()...))
+		}
+		require.NoError(it.Error())
+		require.ElementsMatch([][]byte{[]byte("aa"), []byte("ab")}, got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/compose-spec/compose-go/types"
 	"gopkg.in/yaml.v3"
@@ -19,16 +20,168 @@ import (
 	"github.com/ava-labs/avalanchego/utils/perms"
 )
 
+const (
+	networkName        = "avalanche-testnet"
+	baseNetworkAddress = "10.0.20"
+
+	// workloadBaseAddressOffset is the offset from [baseNetworkAddress]
+	// that the first workload service is assigned. Node addresses are
+	// assigned starting at offset 3, so this leaves ample room to grow
+	// the node count without colliding with workload addresses.
+	workloadBaseAddressOffset = 129
+
+	// devnetGenesisVolumeSource and devnetGenesisTarget locate the shared,
+	// caller-supplied genesis file bind-mounted into every node when
+	// [NetworkModeDevnet] is selected.
+	devnetGenesisVolumeSource = "./volumes/shared/genesis.json"
+	devnetGenesisTarget       = "/root/.avalanchego/configs/genesis.json"
+
+	// Defaults applied to a NodeRuntime field left at its zero value.
+	defaultNodeCPUs            = "2.0"
+	defaultNodeMemory          = types.UnitBytes(4 * 1024 * 1024 * 1024) // 4GiB
+	defaultNodeRestartPolicy   = "on-failure"
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultHealthCheckRetries  = uint64(5)
+)
+
+// NetworkMode selects the network-id/genesis configuration emitted for the
+// generated compose project.
+type NetworkMode int
+
+const (
+	// NetworkModeLocal uses the local-network genesis and network ID
+	// already embedded in the [tmpnet.Network] being compose-ified.
+	NetworkModeLocal NetworkMode = iota
+	// NetworkModeDevnet uses a caller-supplied network ID and genesis,
+	// written to a shared bind-mount so every node can reference it.
+	NetworkModeDevnet
+)
+
+// DevnetConfig supplies the network ID, genesis content, and bootstrapper
+// set for a [NetworkModeDevnet] compose project.
+type DevnetConfig struct {
+	// NetworkID is the custom network ID nodes should report (e.g. 1338).
+	NetworkID uint32
+	// GenesisContent is the raw JSON of the devnet genesis, written to
+	// [devnetGenesisVolumeSource] and bind-mounted into every node.
+	GenesisContent string
+	// BootstrapperIndices identifies which nodes in Network.Nodes should
+	// act as bootstrappers for the rest of the network. If empty, only
+	// the first node is used.
+	BootstrapperIndices []int
+}
+
+// NodeRuntime configures the resource limits, healthcheck, and restart
+// policy applied to every node service in the generated compose project.
+// A zero value field falls back to a sensible default so that existing
+// callers that don't set Runtime are unaffected.
+type NodeRuntime struct {
+	// CPUs is the number of CPUs each node is limited to, e.g. "2.0".
+	CPUs string
+	// Memory is the memory limit applied to each node.
+	Memory types.UnitBytes
+	// RestartPolicy controls how docker restarts a node on failure, e.g.
+	// "on-failure" or "always".
+	RestartPolicy string
+	// HealthCheckInterval, HealthCheckTimeout, and HealthCheckRetries
+	// configure the healthcheck hitting the node's /ext/health endpoint.
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	HealthCheckRetries  uint64
+}
+
+// withDefaults returns a copy of [r] with any zero-valued field replaced by
+// its default.
+func (r NodeRuntime) withDefaults() NodeRuntime {
+	if r.CPUs == "" {
+		r.CPUs = defaultNodeCPUs
+	}
+	if r.Memory == 0 {
+		r.Memory = defaultNodeMemory
+	}
+	if r.RestartPolicy == "" {
+		r.RestartPolicy = defaultNodeRestartPolicy
+	}
+	if r.HealthCheckInterval == 0 {
+		r.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if r.HealthCheckTimeout == 0 {
+		r.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	if r.HealthCheckRetries == 0 {
+		r.HealthCheckRetries = defaultHealthCheckRetries
+	}
+	return r
+}
+
+// WorkloadConfig defines a single workload service (e.g. the default
+// antithesis workload, or an xsvm-specific workload) to include in the
+// generated compose project.
+type WorkloadConfig struct {
+	// Name uniquely identifies the workload service.
+	Name string
+	// ImageName is the docker image the workload container is run from.
+	ImageName string
+	// Env supplies additional environment variables for the workload
+	// container (e.g. chain IDs or funded key material). AVAWL_URIS is
+	// populated automatically with the set of node URIs and does not
+	// need to be set here.
+	Env map[string]string
+}
+
+// SubnetConfig describes a subnet or chain configuration file that should
+// be bind-mounted into every node's configs directory, in addition to the
+// staking material configured via flags.
+type SubnetConfig struct {
+	// Filename is the name the config file is given on disk, e.g.
+	// "C.json" or "<subnetID>.json".
+	Filename string
+	// Content is the raw content written to the config file.
+	Content string
+}
+
+// ComposeConfig parameterizes the docker-compose project generated for an
+// Antithesis test setup, allowing the topology (avalanchego, xsvm, or a
+// user-defined combination of subnets and workloads) to vary by caller.
+type ComposeConfig struct {
+	Network       *tmpnet.Network
+	NodeImageName string
+
+	// Workloads lists the workload services to include. At least one
+	// workload must be specified.
+	Workloads []WorkloadConfig
+
+	// SubnetConfigs are bind-mounted into every node's configs directory.
+	SubnetConfigs []SubnetConfig
+
+	// NetworkMode selects between the network's embedded local genesis
+	// and a caller-supplied devnet genesis. Defaults to NetworkModeLocal.
+	NetworkMode NetworkMode
+	// Devnet configures the network ID and genesis used when NetworkMode
+	// is NetworkModeDevnet. Ignored otherwise.
+	Devnet DevnetConfig
+
+	// Labels are applied to every service and the network in the
+	// generated compose project, e.g. a run ID, git SHA, branch, test
+	// suite, and owner, so downstream log/metric aggregation and cleanup
+	// tooling can filter Antithesis resources by label.
+	Labels map[string]string
+	// NodeLabels extends or overrides Labels for the node at the given
+	// index in Network.Nodes.
+	NodeLabels map[int]map[string]string
+
+	// Runtime configures resource limits, healthchecks, and the restart
+	// policy applied to every node service. Defaults to reasonable values
+	// for a long-running Antithesis test.
+	Runtime NodeRuntime
+}
+
 // Initialize the given path with the docker-compose configuration (compose file and
 // volumes) needed for an Antithesis test setup.
-func GenerateComposeConfig(
-	network *tmpnet.Network,
-	nodeImageName string,
-	workloadImageName string,
-	targetPath string,
-) error {
+func GenerateComposeConfig(cfg *ComposeConfig, targetPath string) error {
 	// Generate a compose project for the specified network
-	project, err := newComposeProject(network, nodeImageName, workloadImageName)
+	project, err := newComposeProject(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create compose project: %w", err)
 	}
@@ -52,12 +205,44 @@ func GenerateComposeConfig(
 		return fmt.Errorf("failed to write genesis: %w", err)
 	}
 
-	// Create the volume paths
+	// Create the volume paths. A volume whose source names a file (e.g. a
+	// mounted config file) only needs its parent directory created; the
+	// file itself is written separately below.
 	for _, service := range project.Services {
 		for _, volume := range service.Volumes {
 			volumePath := filepath.Join(absPath, volume.Source)
-			if err := os.MkdirAll(volumePath, perms.ReadWriteExecute); err != nil {
-				return fmt.Errorf("failed to create volume path %q: %w", volumePath, err)
+			dirPath := volumePath
+			if filepath.Ext(volumePath) != "" {
+				dirPath = filepath.Dir(volumePath)
+			}
+			if err := os.MkdirAll(dirPath, perms.ReadWriteExecute); err != nil {
+				return fmt.Errorf("failed to create volume path %q: %w", dirPath, err)
+			}
+		}
+	}
+
+	if cfg.NetworkMode == NetworkModeDevnet {
+		genesisPath := filepath.Join(absPath, devnetGenesisVolumeSource)
+		if err := os.WriteFile(genesisPath, []byte(cfg.Devnet.GenesisContent), perms.ReadWrite); err != nil {
+			return fmt.Errorf("failed to write devnet genesis: %w", err)
+		}
+	}
+
+	// Write the content of each subnet/chain config file into the node
+	// volume paths created above.
+	contentByFilename := make(map[string]string, len(cfg.SubnetConfigs))
+	for _, subnetConfig := range cfg.SubnetConfigs {
+		contentByFilename[subnetConfig.Filename] = subnetConfig.Content
+	}
+	for _, service := range project.Services {
+		for _, volume := range service.Volumes {
+			content, ok := contentByFilename[filepath.Base(volume.Source)]
+			if !ok {
+				continue
+			}
+			configPath := filepath.Join(absPath, volume.Source)
+			if err := os.WriteFile(configPath, []byte(content), perms.ReadWrite); err != nil {
+				return fmt.Errorf("failed to write subnet config %q: %w", configPath, err)
 			}
 		}
 	}
@@ -65,18 +250,57 @@ func GenerateComposeConfig(
 }
 
 // Create a new docker compose project for an antithesis test setup
-// for the provided network configuration.
-func newComposeProject(network *tmpnet.Network, nodeImageName string, workloadImageName string) (*types.Project, error) {
-	networkName := "avalanche-testnet"
-	baseNetworkAddress := "10.0.20"
-
-	services := make(types.Services, len(network.Nodes)+1)
-	uris := make([]string, len(network.Nodes))
-	var (
-		bootstrapIP  string
-		bootstrapIDs string
-	)
-	for i, node := range network.Nodes {
+// for the provided configuration.
+func newComposeProject(cfg *ComposeConfig) (*types.Project, error) {
+	if len(cfg.Workloads) == 0 {
+		return nil, fmt.Errorf("at least one workload must be specified")
+	}
+	for _, subnetConfig := range cfg.SubnetConfigs {
+		// The volume-mount setup in GenerateComposeConfig distinguishes a
+		// file mount from a directory mount by checking for a file
+		// extension; an extensionless filename would be silently treated
+		// as a directory and fail later with an opaque "is a directory"
+		// error when its content is written, so reject it up front here.
+		if subnetConfig.Filename == "" || filepath.Ext(subnetConfig.Filename) == "" {
+			return nil, fmt.Errorf("subnet config filename %q must be non-empty and include a file extension", subnetConfig.Filename)
+		}
+	}
+
+	bootstrapperIndices := cfg.Devnet.BootstrapperIndices
+	if cfg.NetworkMode != NetworkModeDevnet || len(bootstrapperIndices) == 0 {
+		bootstrapperIndices = []int{0}
+	}
+	isBootstrapper := make(map[int]bool, len(bootstrapperIndices))
+	for _, idx := range bootstrapperIndices {
+		if idx < 0 || idx >= len(cfg.Network.Nodes) {
+			return nil, fmt.Errorf("bootstrapper index %d out of range for %d nodes", idx, len(cfg.Network.Nodes))
+		}
+		isBootstrapper[idx] = true
+	}
+
+	// bootstrapArgs returns the comma-joined bootstrap IPs and node IDs for
+	// every bootstrapper other than excludeIdx, so that a bootstrapper
+	// node (excludeIdx set to its own index) learns about its peer
+	// bootstrappers instead of itself, while a non-bootstrapper node
+	// (excludeIdx -1) learns about all of them.
+	bootstrapArgs := func(excludeIdx int) (ipsArg, idsArg string) {
+		var ips, ids []string
+		for _, idx := range bootstrapperIndices {
+			if idx == excludeIdx {
+				continue
+			}
+			ips = append(ips, fmt.Sprintf("%s.%d:9651", baseNetworkAddress, 3+idx))
+			ids = append(ids, cfg.Network.Nodes[idx].NodeID.String())
+		}
+		return strings.Join(ips, ","), strings.Join(ids, ",")
+	}
+	bootstrapIPsArg, bootstrapIDsArg := bootstrapArgs(-1)
+
+	runtime := cfg.Runtime.withDefaults()
+
+	services := make(types.Services, len(cfg.Network.Nodes)+len(cfg.Workloads))
+	uris := make([]string, len(cfg.Network.Nodes))
+	for i, node := range cfg.Network.Nodes {
 		address := fmt.Sprintf("%s.%d", baseNetworkAddress, 3+i)
 
 		tlsKey, err := node.Flags.GetStringVal(config.StakingTLSKeyContentKey)
@@ -92,8 +316,13 @@ func newComposeProject(network *tmpnet.Network, nodeImageName string, workloadIm
 			return nil, err
 		}
 
+		avagoNetworkID := constants.LocalName
+		if cfg.NetworkMode == NetworkModeDevnet {
+			avagoNetworkID = fmt.Sprintf("%d", cfg.Devnet.NetworkID)
+		}
+
 		env := types.Mapping{
-			config.NetworkNameKey:             constants.LocalName,
+			config.NetworkNameKey:             avagoNetworkID,
 			config.AdminAPIEnabledKey:         "true",
 			config.LogLevelKey:                logging.Debug.String(),
 			config.LogDisplayLevelKey:         logging.Trace.String(),
@@ -103,35 +332,92 @@ func newComposeProject(network *tmpnet.Network, nodeImageName string, workloadIm
 			config.StakingCertContentKey:      tlsCert,
 			config.StakingSignerKeyContentKey: signerKey,
 		}
+		if cfg.NetworkMode == NetworkModeDevnet {
+			env[config.GenesisFileKey] = devnetGenesisTarget
+		}
 
 		nodeName := "avalanche"
-		if i == 0 {
-			nodeName += "-bootstrap-node"
-			bootstrapIP = address + ":9651"
-			bootstrapIDs = node.NodeID.String()
+		if isBootstrapper[i] {
+			if i == 0 {
+				nodeName += "-bootstrap-node"
+			} else {
+				nodeName = fmt.Sprintf("%s-bootstrap-node-%d", nodeName, i+1)
+			}
+			// Other bootstrappers, if any, so bootstrapper nodes connect
+			// directly to each other rather than only being reachable
+			// from non-bootstrapper nodes.
+			if peerIPsArg, peerIDsArg := bootstrapArgs(i); peerIPsArg != "" {
+				env[config.BootstrapIPsKey] = peerIPsArg
+				env[config.BootstrapIDsKey] = peerIDsArg
+			}
 		} else {
 			nodeName = fmt.Sprintf("%s-node-%d", nodeName, i+1)
-			env[config.BootstrapIPsKey] = bootstrapIP
-			env[config.BootstrapIDsKey] = bootstrapIDs
+			env[config.BootstrapIPsKey] = bootstrapIPsArg
+			env[config.BootstrapIDsKey] = bootstrapIDsArg
 		}
 
 		// The env is defined with the keys and then converted to env
 		// vars because only the keys are available as constants.
 		env = keyMapToEnvVarMap(env)
 
-		services[i+1] = types.ServiceConfig{
+		volumes := []types.ServiceVolumeConfig{
+			{
+				Type:   types.VolumeTypeBind,
+				Source: fmt.Sprintf("./volumes/%s/logs", nodeName),
+				Target: "/root/.avalanchego/logs",
+			},
+		}
+		for _, subnetConfig := range cfg.SubnetConfigs {
+			subnetConfigPath := fmt.Sprintf("./volumes/%s/configs/%s", nodeName, subnetConfig.Filename)
+			volumes = append(volumes, types.ServiceVolumeConfig{
+				Type:   types.VolumeTypeBind,
+				Source: subnetConfigPath,
+				Target: "/root/.avalanchego/configs/" + subnetConfig.Filename,
+			})
+		}
+		if cfg.NetworkMode == NetworkModeDevnet {
+			volumes = append(volumes, types.ServiceVolumeConfig{
+				Type:   types.VolumeTypeBind,
+				Source: devnetGenesisVolumeSource,
+				Target: devnetGenesisTarget,
+			})
+		}
+
+		healthCheckInterval := types.Duration(runtime.HealthCheckInterval)
+		healthCheckTimeout := types.Duration(runtime.HealthCheckTimeout)
+		healthCheckRetries := runtime.HealthCheckRetries
+
+		services[i+len(cfg.Workloads)] = types.ServiceConfig{
 			Name:          nodeName,
 			ContainerName: nodeName,
 			Hostname:      nodeName,
-			Image:         nodeImageName,
-			Volumes: []types.ServiceVolumeConfig{
-				{
-					Type:   types.VolumeTypeBind,
-					Source: fmt.Sprintf("./volumes/%s/logs", nodeName),
-					Target: "/root/.avalanchego/logs",
+			Image:         cfg.NodeImageName,
+			Volumes:       volumes,
+			Environment:   env.ToMappingWithEquals(),
+			Labels:        mergeLabels(cfg.Labels, cfg.NodeLabels[i]),
+			Restart:       runtime.RestartPolicy,
+			Deploy: &types.DeployConfig{
+				Resources: types.Resources{
+					Limits: &types.Resource{
+						NanoCPUs:    runtime.CPUs,
+						MemoryBytes: runtime.Memory,
+					},
 				},
 			},
-			Environment: env.ToMappingWithEquals(),
+			HealthCheck: &types.HealthCheckConfig{
+				// The antithesis node image doesn't ship curl or wget, but
+				// does ship bash, so the check is run explicitly under
+				// bash (rather than via CMD-SHELL, whose default shell may
+				// not support /dev/tcp) to use its /dev/tcp pseudo-device
+				// in place of an external HTTP client.
+				Test: types.HealthCheckTest{
+					"CMD", "bash", "-c",
+					`exec 3<>/dev/tcp/127.0.0.1/9650 && printf 'GET /ext/health HTTP/1.0\r\n\r\n' >&3 && grep -q '"healthy":true' <&3`,
+				},
+				Interval: &healthCheckInterval,
+				Timeout:  &healthCheckTimeout,
+				Retries:  &healthCheckRetries,
+			},
 			Networks: map[string]*types.ServiceNetworkConfig{
 				networkName: {
 					Ipv4Address: address,
@@ -139,26 +425,32 @@ func newComposeProject(network *tmpnet.Network, nodeImageName string, workloadIm
 			},
 		}
 
-		// Collect URIs for the workload container
+		// Collect URIs for the workload containers
 		uris[i] = fmt.Sprintf("http://%s:9650", address)
 	}
 
-	workloadEnv := types.Mapping{
-		"AVAWL_URIS": strings.Join(uris, " "),
-	}
+	for i, workload := range cfg.Workloads {
+		workloadEnv := types.Mapping{
+			"AVAWL_URIS": strings.Join(uris, " "),
+		}
+		for k, v := range workload.Env {
+			workloadEnv[k] = v
+		}
 
-	workloadName := "workload"
-	services[0] = types.ServiceConfig{
-		Name:          workloadName,
-		ContainerName: workloadName,
-		Hostname:      workloadName,
-		Image:         workloadImageName,
-		Environment:   workloadEnv.ToMappingWithEquals(),
-		Networks: map[string]*types.ServiceNetworkConfig{
-			networkName: {
-				Ipv4Address: baseNetworkAddress + ".129",
+		address := fmt.Sprintf("%s.%d", baseNetworkAddress, workloadBaseAddressOffset+i)
+		services[i] = types.ServiceConfig{
+			Name:          workload.Name,
+			ContainerName: workload.Name,
+			Hostname:      workload.Name,
+			Image:         workload.ImageName,
+			Environment:   workloadEnv.ToMappingWithEquals(),
+			Labels:        mergeLabels(cfg.Labels, nil),
+			Networks: map[string]*types.ServiceNetworkConfig{
+				networkName: {
+					Ipv4Address: address,
+				},
 			},
-		},
+		}
 	}
 
 	return &types.Project{
@@ -172,12 +464,28 @@ func newComposeProject(network *tmpnet.Network, nodeImageName string, workloadIm
 						},
 					},
 				},
+				Labels: mergeLabels(cfg.Labels, nil),
 			},
 		},
 		Services: services,
 	}, nil
 }
 
+// mergeLabels combines [base] with [overrides], with [overrides] taking
+// precedence on key collisions. Bind-mounted volumes (the only kind this
+// generator emits) don't carry labels in the compose spec, so only
+// services and the network are labeled.
+func mergeLabels(base, overrides map[string]string) types.Labels {
+	labels := make(types.Labels, len(base)+len(overrides))
+	for k, v := range base {
+		labels[k] = v
+	}
+	for k, v := range overrides {
+		labels[k] = v
+	}
+	return labels
+}
+
 // Convert a mapping of avalanche config keys to a mapping of env vars
 func keyMapToEnvVarMap(keyMap types.Mapping) types.Mapping {
 	envVarMap := make(types.Mapping, len(keyMap))
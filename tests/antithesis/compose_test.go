@@ -0,0 +1,228 @@
+// Copyright (C) 2019-2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package antithesis
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/tests/fixture/tmpnet"
+)
+
+// newTestNetwork returns a [tmpnet.Network] with n nodes carrying just
+// enough staking material in their flags for newComposeProject to run.
+func newTestNetwork(n int) *tmpnet.Network {
+	nodes := make([]*tmpnet.Node, n)
+	for i := range nodes {
+		nodes[i] = &tmpnet.Node{
+			NodeID: ids.GenerateTestNodeID(),
+			Flags: tmpnet.FlagsMap{
+				config.StakingTLSKeyContentKey:    "tls-key",
+				config.StakingCertContentKey:      "tls-cert",
+				config.StakingSignerKeyContentKey: "signer-key",
+			},
+		}
+	}
+	return &tmpnet.Network{Nodes: nodes}
+}
+
+func newTestConfig(n int) *ComposeConfig {
+	return &ComposeConfig{
+		Network:       newTestNetwork(n),
+		NodeImageName: "avaplatform/avalanchego:latest",
+		Workloads: []WorkloadConfig{
+			{Name: "workload", ImageName: "antithesis-workload:latest"},
+		},
+	}
+}
+
+// envVarName reproduces keyMapToEnvVarMap's key transform so tests don't
+// need to hard-code the resulting env var name.
+func envVarName(key string) string {
+	for envVar := range keyMapToEnvVarMap(types.Mapping{key: ""}) {
+		return envVar
+	}
+	return ""
+}
+
+func envVal(env types.MappingWithEquals, key string) string {
+	v, ok := env[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return *v
+}
+
+func serviceByName(services types.Services, name string) *types.ServiceConfig {
+	for i, service := range services {
+		if service.Name == name {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+func TestNewComposeProjectMultiWorkload(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(2)
+	cfg.Workloads = []WorkloadConfig{
+		{Name: "workload-avalanchego"},
+		{Name: "workload-xsvm", ImageName: "xsvm:latest", Env: map[string]string{"XSVM_CHAIN_ID": "abc"}},
+	}
+
+	project, err := newComposeProject(cfg)
+	require.NoError(err)
+	require.Len(project.Services, len(cfg.Network.Nodes)+len(cfg.Workloads))
+
+	wantURIs := "http://10.0.20.3:9650 http://10.0.20.4:9650"
+	for _, name := range []string{"workload-avalanchego", "workload-xsvm"} {
+		service := serviceByName(project.Services, name)
+		require.NotNil(service, "missing service %q", name)
+		require.Equal(wantURIs, envVal(service.Environment, "AVAWL_URIS"))
+	}
+
+	xsvm := serviceByName(project.Services, "workload-xsvm")
+	require.Equal("abc", envVal(xsvm.Environment, "XSVM_CHAIN_ID"))
+}
+
+func TestNewComposeProjectSubnetConfigMounts(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(1)
+	cfg.SubnetConfigs = []SubnetConfig{
+		{Filename: "C.json", Content: `{"chain":"C"}`},
+		{Filename: "subnet.json", Content: `{"subnet":true}`},
+	}
+
+	project, err := newComposeProject(cfg)
+	require.NoError(err)
+
+	node := serviceByName(project.Services, "avalanche-bootstrap-node")
+	require.NotNil(node)
+
+	wantVolumes := map[string]string{
+		"./volumes/avalanche-bootstrap-node/configs/C.json":      "/root/.avalanchego/configs/C.json",
+		"./volumes/avalanche-bootstrap-node/configs/subnet.json": "/root/.avalanchego/configs/subnet.json",
+	}
+	for source, target := range wantVolumes {
+		found := false
+		for _, volume := range node.Volumes {
+			if volume.Source == source {
+				require.Equal(target, volume.Target)
+				found = true
+			}
+		}
+		require.True(found, "missing volume mount for %q", source)
+	}
+}
+
+func TestNewComposeProjectRejectsExtensionlessSubnetConfigFilename(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(1)
+	cfg.SubnetConfigs = []SubnetConfig{{Filename: "genesis", Content: "{}"}}
+
+	_, err := newComposeProject(cfg)
+	require.ErrorContains(err, "must be non-empty and include a file extension")
+}
+
+func TestNewComposeProjectLabels(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(2)
+	cfg.Labels = map[string]string{"run-id": "abc123", "owner": "antithesis"}
+	cfg.NodeLabels = map[int]map[string]string{
+		1: {"owner": "node-1-owner", "role": "bootstrap"},
+	}
+
+	project, err := newComposeProject(cfg)
+	require.NoError(err)
+
+	// cfg.Labels lands on the network...
+	require.Equal(types.Labels(cfg.Labels), project.Networks[networkName].Labels)
+
+	// ...and on every node and workload service that has no override.
+	node0 := serviceByName(project.Services, "avalanche-bootstrap-node")
+	require.NotNil(node0)
+	require.Equal(types.Labels(cfg.Labels), node0.Labels)
+
+	workload := serviceByName(project.Services, "workload")
+	require.NotNil(workload)
+	require.Equal(types.Labels(cfg.Labels), workload.Labels)
+
+	// cfg.NodeLabels[i] extends/overrides cfg.Labels for that node only.
+	node1 := serviceByName(project.Services, "avalanche-node-2")
+	require.NotNil(node1)
+	require.Equal(types.Labels{
+		"run-id": "abc123",
+		"owner":  "node-1-owner",
+		"role":   "bootstrap",
+	}, node1.Labels)
+}
+
+func TestNewComposeProjectDevnetMultipleBootstrappers(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(3)
+	cfg.NetworkMode = NetworkModeDevnet
+	cfg.Devnet = DevnetConfig{
+		NetworkID:           1338,
+		GenesisContent:      "{}",
+		BootstrapperIndices: []int{0, 1},
+	}
+
+	project, err := newComposeProject(cfg)
+	require.NoError(err)
+
+	ipsKey := envVarName(config.BootstrapIPsKey)
+	idsKey := envVarName(config.BootstrapIDsKey)
+
+	bootstrap0 := serviceByName(project.Services, "avalanche-bootstrap-node")
+	bootstrap1 := serviceByName(project.Services, "avalanche-bootstrap-node-2")
+	nonBootstrap := serviceByName(project.Services, "avalanche-node-3")
+	require.NotNil(bootstrap0)
+	require.NotNil(bootstrap1)
+	require.NotNil(nonBootstrap)
+
+	// Each bootstrapper learns about the *other* bootstrapper, not itself.
+	require.Equal("10.0.20.4:9651", envVal(bootstrap0.Environment, ipsKey))
+	require.Equal(cfg.Network.Nodes[1].NodeID.String(), envVal(bootstrap0.Environment, idsKey))
+	require.Equal("10.0.20.3:9651", envVal(bootstrap1.Environment, ipsKey))
+	require.Equal(cfg.Network.Nodes[0].NodeID.String(), envVal(bootstrap1.Environment, idsKey))
+
+	// Non-bootstrapper nodes still learn about every bootstrapper.
+	require.Equal("10.0.20.3:9651,10.0.20.4:9651", envVal(nonBootstrap.Environment, ipsKey))
+}
+
+func TestNewComposeProjectDevnetSingleBootstrapperHasNoPeers(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(2)
+	cfg.NetworkMode = NetworkModeDevnet
+	cfg.Devnet = DevnetConfig{NetworkID: 1338, GenesisContent: "{}"}
+
+	project, err := newComposeProject(cfg)
+	require.NoError(err)
+
+	ipsKey := envVarName(config.BootstrapIPsKey)
+	bootstrap0 := serviceByName(project.Services, "avalanche-bootstrap-node")
+	require.NotNil(bootstrap0)
+	require.Empty(envVal(bootstrap0.Environment, ipsKey))
+}
+
+func TestNewComposeProjectDevnetBootstrapperIndexOutOfRange(t *testing.T) {
+	require := require.New(t)
+
+	cfg := newTestConfig(1)
+	cfg.NetworkMode = NetworkModeDevnet
+	cfg.Devnet = DevnetConfig{NetworkID: 1338, GenesisContent: "{}", BootstrapperIndices: []int{5}}
+
+	_, err := newComposeProject(cfg)
+	require.ErrorContains(err, "out of range")
+}